@@ -0,0 +1,56 @@
+package kafka
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+
+	"github.com/Shopify/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// scramClient adapts github.com/xdg-go/scram to the sarama.SCRAMClient
+// interface sarama requires for the SCRAM-SHA-256/512 mechanisms.
+type scramClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.Client = client
+	c.ClientConversation = c.Client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.ClientConversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.ClientConversation.Done()
+}
+
+func sha256HashGenerator() hash.Hash { return sha256.New() }
+func sha512HashGenerator() hash.Hash { return sha512.New() }
+
+// scramClientGeneratorFunc returns the sarama.SCRAMClientGeneratorFunc for
+// the given mechanism, so Authentication.Apply can wire it into
+// sarama.Config.Net.SASL.SCRAMClientGeneratorFunc. Without it, sarama has no
+// way to perform the SCRAM handshake and fails to connect.
+func scramClientGeneratorFunc(mechanism string) func() sarama.SCRAMClient {
+	switch mechanism {
+	case mechanismSCRAM512:
+		return func() sarama.SCRAMClient {
+			return &scramClient{HashGeneratorFcn: sha512HashGenerator}
+		}
+	default:
+		return func() sarama.SCRAMClient {
+			return &scramClient{HashGeneratorFcn: sha256HashGenerator}
+		}
+	}
+}
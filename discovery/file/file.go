@@ -18,8 +18,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/bourbonkk/Clymene/discovery/targetgroup"
+	"github.com/bourbonkk/Clymene/pkg/logging"
 	"github.com/pkg/errors"
-	"go.uber.org/zap"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -165,13 +165,13 @@ type Discovery struct {
 	// and how many target groups they contained.
 	// This is used to detect deleted target groups.
 	lastRefresh map[string]int
-	logger      *zap.Logger
+	logger      *logging.Logger
 }
 
 // NewDiscovery returns a new file discovery for the given paths.
-func NewDiscovery(conf *SDConfig, logger *zap.Logger) *Discovery {
+func NewDiscovery(conf *SDConfig, logger *logging.Logger) *Discovery {
 	if logger == nil {
-		logger = zap.NewNop()
+		logger = logging.NewNop()
 	}
 
 	disc := &Discovery{
@@ -190,7 +190,7 @@ func (d *Discovery) listFiles() []string {
 	for _, p := range d.paths {
 		files, err := filepath.Glob(p)
 		if err != nil {
-			d.logger.Error("Error expanding glob", zap.String("glob", p), zap.Error(err))
+			d.logger.Error("Error expanding glob", "glob", p, "error", err)
 			continue
 		}
 		paths = append(paths, files...)
@@ -211,7 +211,7 @@ func (d *Discovery) watchFiles() {
 			p = "./"
 		}
 		if err := d.watcher.Add(p); err != nil {
-			d.logger.Error("Error adding file watch", zap.String("path", p), zap.Error(err))
+			d.logger.Error("Error adding file watch", "path", p, "error", err)
 		}
 	}
 }
@@ -220,7 +220,7 @@ func (d *Discovery) watchFiles() {
 func (d *Discovery) Run(ctx context.Context, ch chan<- []*targetgroup.Group) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		d.logger.Error("Error adding file watcher", zap.Error(err))
+		d.logger.Error("Error adding file watcher", "error", err)
 		return
 	}
 	d.watcher = watcher
@@ -259,7 +259,7 @@ func (d *Discovery) Run(ctx context.Context, ch chan<- []*targetgroup.Group) {
 
 		case err := <-d.watcher.Errors:
 			if err != nil {
-				d.logger.Error("Error watching file", zap.Error(err))
+				d.logger.Error("Error watching file", "error", err)
 			}
 		}
 	}
@@ -279,7 +279,7 @@ func (d *Discovery) deleteTimestamp(filename string) {
 
 // stop shuts down the file watcher.
 func (d *Discovery) stop() {
-	d.logger.Debug("Stopping file discovery...", zap.String("paths", fmt.Sprintf("%v", d.paths)))
+	d.logger.Debug("Stopping file discovery...", "paths", fmt.Sprintf("%v", d.paths))
 
 	done := make(chan struct{})
 	defer close(done)
@@ -299,7 +299,7 @@ func (d *Discovery) stop() {
 		}
 	}()
 	if err := d.watcher.Close(); err != nil {
-		d.logger.Error("Error closing file watcher", zap.String("paths", fmt.Sprintf("%v", d.paths)), zap.Error(err))
+		d.logger.Error("Error closing file watcher", "paths", fmt.Sprintf("%v", d.paths), "error", err)
 	}
 
 	d.logger.Debug("File discovery stopped")
@@ -318,7 +318,7 @@ func (d *Discovery) refresh(ctx context.Context, ch chan<- []*targetgroup.Group)
 		if err != nil {
 			fileSDReadErrorsCount.Inc()
 
-			d.logger.Error("Error reading file", zap.String("path", p), zap.Error(err))
+			d.logger.Error("Error reading file", "path", p, "error", err)
 			// Prevent deletion down below.
 			ref[p] = d.lastRefresh[p]
 			continue
@@ -335,7 +335,7 @@ func (d *Discovery) refresh(ctx context.Context, ch chan<- []*targetgroup.Group)
 	for f, n := range d.lastRefresh {
 		m, ok := ref[f]
 		if !ok || n > m {
-			d.logger.Debug("file_sd refresh found file that should be removed", zap.String("file", f))
+			d.logger.Debug("file_sd refresh found file that should be removed", "file", f)
 			d.deleteTimestamp(f)
 			for i := m; i < n; i++ {
 				select {
@@ -0,0 +1,273 @@
+// Copyright 2015 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bourbonkk/Clymene/discovery/targetgroup"
+	"github.com/bourbonkk/Clymene/pkg/logging"
+	"github.com/pkg/errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+)
+
+var (
+	// DefaultSDConfig is the default http SD configuration.
+	DefaultSDConfig = SDConfig{
+		RefreshInterval:  model.Duration(1 * time.Minute),
+		HTTPClientConfig: config.DefaultHTTPClientConfig,
+	}
+
+	httpSDRefreshDuration = prometheus.NewSummary(
+		prometheus.SummaryOpts{
+			Name:       "prometheus_sd_http_scan_duration_seconds",
+			Help:       "The duration of the HTTP-SD scan in seconds.",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		})
+	httpSDReadErrorsCount = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "prometheus_sd_http_read_errors_total",
+			Help: "The number of HTTP-SD read errors.",
+		})
+)
+
+func init() {
+	prometheus.MustRegister(httpSDRefreshDuration)
+	prometheus.MustRegister(httpSDReadErrorsCount)
+}
+
+// SDConfig is the configuration for HTTP based discovery.
+type SDConfig struct {
+	HTTPClientConfig config.HTTPClientConfig `yaml:",inline"`
+	RefreshInterval  model.Duration          `yaml:"refresh_interval,omitempty"`
+	URL              string                  `yaml:"url"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SDConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultSDConfig
+	type plain SDConfig
+	err := unmarshal((*plain)(c))
+	if err != nil {
+		return err
+	}
+	if c.URL == "" {
+		return errors.New("URL is missing")
+	}
+	parsedURL, err := url.Parse(c.URL)
+	if err != nil {
+		return err
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return errors.New("URL scheme must be 'http' or 'https'")
+	}
+	if parsedURL.Host == "" {
+		return errors.New("host is missing in URL")
+	}
+	return c.HTTPClientConfig.Validate()
+}
+
+// Discovery provides service discovery functionality based
+// on HTTP endpoints that return target groups in JSON format.
+type Discovery struct {
+	url      string
+	client   *http.Client
+	interval time.Duration
+	logger   *logging.Logger
+
+	lock         sync.RWMutex
+	etag         string
+	lastModified string
+	cacheUntil   time.Time
+	lastGroups   []*targetgroup.Group
+}
+
+// NewDiscovery returns a new HTTP discovery for the given config.
+func NewDiscovery(conf *SDConfig, logger *logging.Logger) (*Discovery, error) {
+	if logger == nil {
+		logger = logging.NewNop()
+	}
+	client, err := config.NewClientFromConfig(conf.HTTPClientConfig, "http")
+	if err != nil {
+		return nil, err
+	}
+	d := &Discovery{
+		url:      conf.URL,
+		client:   client,
+		interval: time.Duration(conf.RefreshInterval),
+		logger:   logger,
+	}
+	return d, nil
+}
+
+// Run implements the Discoverer interface.
+func (d *Discovery) Run(ctx context.Context, ch chan<- []*targetgroup.Group) {
+	d.refresh(ctx, ch)
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.refresh(ctx, ch)
+		}
+	}
+}
+
+// refresh polls the configured URL and, on success, sends the resulting target
+// groups through the channel. Failed refreshes keep serving the last good
+// targets rather than blanking them out.
+func (d *Discovery) refresh(ctx context.Context, ch chan<- []*targetgroup.Group) {
+	t0 := time.Now()
+	defer func() {
+		httpSDRefreshDuration.Observe(time.Since(t0).Seconds())
+	}()
+
+	tgroups, unchanged, err := d.fetch(ctx)
+	if err != nil {
+		httpSDReadErrorsCount.Inc()
+		d.logger.Error("Error refreshing HTTP-SD", "url", d.url, "error", err)
+		return
+	}
+	if unchanged {
+		return
+	}
+
+	select {
+	case ch <- tgroups:
+	case <-ctx.Done():
+	}
+}
+
+// fetch issues a conditional GET against the configured URL, honoring
+// Cache-Control/ETag so unchanged responses don't cost a re-parse. unchanged
+// is true when the response is still fresh, either because a prior
+// Cache-Control: max-age hasn't elapsed yet or because the server responded
+// 304 Not Modified. On a genuine 200, it stamps each group's Source (so it's
+// stable across refreshes) and records the result as lastGroups, the same
+// groups a subsequent cache hit returns.
+func (d *Discovery) fetch(ctx context.Context) (tgroups []*targetgroup.Group, unchanged bool, err error) {
+	d.lock.RLock()
+	etag := d.etag
+	lastModified := d.lastModified
+	cacheUntil := d.cacheUntil
+	lastGroups := d.lastGroups
+	d.lock.RUnlock()
+
+	if !cacheUntil.IsZero() && time.Now().Before(cacheUntil) {
+		return lastGroups, true, nil
+	}
+
+	req, err := http.NewRequest("GET", d.url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		d.lock.Lock()
+		d.cacheUntil = cacheControlExpiry(resp.Header.Get("Cache-Control"))
+		d.lock.Unlock()
+		return lastGroups, true, nil
+	}
+
+	if resp.StatusCode/100 != 2 {
+		return nil, false, errors.Errorf("server returned HTTP status %s", resp.Status)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := json.Unmarshal(b, &tgroups); err != nil {
+		return nil, false, err
+	}
+
+	for i, tg := range tgroups {
+		if tg == nil {
+			continue
+		}
+		tg.Source = httpSource(d.url, i)
+		if tg.Labels == nil {
+			tg.Labels = model.LabelSet{}
+		}
+	}
+
+	d.lock.Lock()
+	d.etag = resp.Header.Get("ETag")
+	d.lastModified = resp.Header.Get("Last-Modified")
+	d.cacheUntil = cacheControlExpiry(resp.Header.Get("Cache-Control"))
+	d.lastGroups = tgroups
+	d.lock.Unlock()
+
+	return tgroups, false, nil
+}
+
+// cacheControlExpiry parses a Cache-Control response header for a
+// "max-age=N" directive and returns the time until which the response may be
+// treated as fresh without revalidating. It returns the zero Time when no
+// usable max-age is present or the response must not be cached.
+func cacheControlExpiry(cacheControl string) time.Time {
+	if cacheControl == "" {
+		return time.Time{}
+	}
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		if strings.EqualFold(directive, "no-cache") || strings.EqualFold(directive, "no-store") {
+			return time.Time{}
+		}
+		if !strings.HasPrefix(strings.ToLower(directive), "max-age=") {
+			continue
+		}
+		secs, err := strconv.Atoi(directive[len("max-age="):])
+		if err != nil || secs <= 0 {
+			return time.Time{}
+		}
+		return time.Now().Add(time.Duration(secs) * time.Second)
+	}
+	return time.Time{}
+}
+
+// httpSource returns a source ID for the i-th target group fetched from url.
+func httpSource(url string, i int) string {
+	return fmt.Sprintf("%s:%s", url, strconv.Itoa(i))
+}
@@ -0,0 +1,125 @@
+// Copyright 2015 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bourbonkk/Clymene/discovery/targetgroup"
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheControlExpiry(t *testing.T) {
+	assert.True(t, cacheControlExpiry("").IsZero())
+	assert.True(t, cacheControlExpiry("no-cache").IsZero())
+	assert.True(t, cacheControlExpiry("no-store").IsZero())
+	assert.True(t, cacheControlExpiry("max-age=0").IsZero())
+	assert.True(t, cacheControlExpiry("max-age=not-a-number").IsZero())
+
+	expiry := cacheControlExpiry("max-age=60")
+	assert.WithinDuration(t, time.Now().Add(60*time.Second), expiry, 2*time.Second)
+}
+
+func TestHTTPSourceIsStablePerURLAndIndex(t *testing.T) {
+	assert.Equal(t, httpSource("http://example.com/targets", 0), httpSource("http://example.com/targets", 0))
+	assert.NotEqual(t, httpSource("http://example.com/targets", 0), httpSource("http://example.com/targets", 1))
+	assert.NotEqual(t, httpSource("http://a.example.com/targets", 0), httpSource("http://b.example.com/targets", 0))
+}
+
+func TestDiscoveryHonorsConditionalGET(t *testing.T) {
+	var (
+		requests    int
+		ifNoneMatch []string
+		ifModSince  []string
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/targets", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		ifNoneMatch = append(ifNoneMatch, r.Header.Get("If-None-Match"))
+		ifModSince = append(ifModSince, r.Header.Get("If-Modified-Since"))
+
+		if requests == 1 {
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Last-Modified", "Mon, 01 Jan 2024 00:00:00 GMT")
+			w.Write([]byte(`[{"targets":["1.1.1.1:80"]}]`))
+			return
+		}
+		w.WriteHeader(http.StatusNotModified)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	conf := &SDConfig{URL: srv.URL + "/targets", HTTPClientConfig: config.DefaultHTTPClientConfig, RefreshInterval: model.Duration(time.Minute)}
+	d, err := NewDiscovery(conf, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	tgroups, unchanged, err := d.fetch(ctx)
+	require.NoError(t, err)
+	assert.False(t, unchanged)
+	require.Len(t, tgroups, 1)
+	assert.Equal(t, httpSource(conf.URL, 0), tgroups[0].Source)
+
+	tgroups2, unchanged2, err := d.fetch(ctx)
+	require.NoError(t, err)
+	assert.True(t, unchanged2)
+	assert.Equal(t, tgroups, tgroups2)
+
+	require.Equal(t, 2, requests)
+	assert.Equal(t, `"v1"`, ifNoneMatch[1])
+	assert.Equal(t, "Mon, 01 Jan 2024 00:00:00 GMT", ifModSince[1])
+}
+
+func TestDiscoveryKeepsLastGoodTargetsOnFailedRefresh(t *testing.T) {
+	var fail bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/targets", func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`[{"targets":["1.1.1.1:80"]}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	conf := &SDConfig{URL: srv.URL + "/targets", HTTPClientConfig: config.DefaultHTTPClientConfig, RefreshInterval: model.Duration(time.Minute)}
+	d, err := NewDiscovery(conf, nil)
+	require.NoError(t, err)
+
+	ch := make(chan []*targetgroup.Group, 1)
+	ctx := context.Background()
+	d.refresh(ctx, ch)
+	good := <-ch
+
+	fail = true
+	d.refresh(ctx, ch)
+	select {
+	case <-ch:
+		t.Fatal("a failed refresh must not publish an update")
+	default:
+	}
+
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	assert.Equal(t, good, d.lastGroups)
+}
@@ -0,0 +1,291 @@
+// Copyright 2016 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bourbonkk/Clymene/discovery/file"
+	"github.com/bourbonkk/Clymene/discovery/http"
+	"github.com/bourbonkk/Clymene/discovery/targetgroup"
+	"github.com/bourbonkk/Clymene/pkg/logging"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// debounceWindow is how long the manager waits for a burst of updates from a
+// single provider (e.g. an fsnotify storm) to settle before fanning them out.
+const debounceWindow = 100 * time.Millisecond
+
+var (
+	discoveredTargets = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "clymene_sd_discovered_targets",
+			Help: "Current number of discovered targets.",
+		},
+		[]string{"name"},
+	)
+	lastRefreshTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "clymene_sd_last_refresh_time_seconds",
+			Help: "Timestamp of the last successful refresh for a discovery provider.",
+		},
+		[]string{"name"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(discoveredTargets)
+	prometheus.MustRegister(lastRefreshTimestamp)
+}
+
+// Discoverer is implemented by any service discovery backend (file, http,
+// kubernetes, ...) that can push updated target groups onto a channel until
+// its context is cancelled.
+type Discoverer interface {
+	Run(ctx context.Context, up chan<- []*targetgroup.Group)
+}
+
+// provider pairs a named Discoverer with the cancel function that stops it.
+type provider struct {
+	name   string
+	d      Discoverer
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Manager owns a set of named Discoverers, deduplicates the target groups
+// they emit by Source in an internal cache, and fans out a coalesced view of
+// all providers to consumers through SyncCh.
+type Manager struct {
+	logger *logging.Logger
+
+	mtx       sync.RWMutex
+	providers map[string]*provider
+	// cache holds the last known groups per provider name, keyed by Source
+	// within that provider, so that an update from one provider never drops
+	// the groups most recently seen from another.
+	cache map[string]map[string]*targetgroup.Group
+
+	updates chan updateEvent
+	// syncCh is buffered to depth 1 so send can always hold the latest
+	// coalesced state, even when no consumer is currently reading.
+	syncCh chan map[string][]*targetgroup.Group
+}
+
+type updateEvent struct {
+	name   string
+	groups []*targetgroup.Group
+}
+
+// NewManager creates a ready to use Manager.
+func NewManager(logger *logging.Logger) *Manager {
+	if logger == nil {
+		logger = logging.NewNop()
+	}
+	return &Manager{
+		logger:    logger,
+		providers: make(map[string]*provider),
+		cache:     make(map[string]map[string]*targetgroup.Group),
+		updates:   make(chan updateEvent),
+		syncCh:    make(chan map[string][]*targetgroup.Group, 1),
+	}
+}
+
+// SyncCh returns the channel through which the manager publishes the
+// deduplicated view of all providers' target groups, keyed by provider name.
+func (m *Manager) SyncCh() <-chan map[string][]*targetgroup.Group {
+	return m.syncCh
+}
+
+// Config groups the named file and HTTP SD configurations that ApplyConfig
+// registers with the manager.
+type Config struct {
+	File map[string]*file.SDConfig
+	HTTP map[string]*http.SDConfig
+}
+
+// ApplyConfig builds a Discoverer from each file and HTTP SD configuration
+// in cfg and Registers it with the manager under a name that disambiguates
+// it by SD type, so file.Discovery and http.Discovery both run under this
+// manager instead of being consumed directly by callers.
+func (m *Manager) ApplyConfig(ctx context.Context, cfg Config) error {
+	for name, sdCfg := range cfg.File {
+		m.Register(ctx, "file/"+name, file.NewDiscovery(sdCfg, m.logger))
+	}
+	for name, sdCfg := range cfg.HTTP {
+		d, err := http.NewDiscovery(sdCfg, m.logger)
+		if err != nil {
+			return errors.Wrapf(err, "creating http discoverer %q", name)
+		}
+		m.Register(ctx, "http/"+name, d)
+	}
+	return nil
+}
+
+// Register starts the given Discoverer under name. If a provider already
+// exists under that name it is stopped first, so Register can be called
+// again on config reload.
+func (m *Manager) Register(ctx context.Context, name string, d Discoverer) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if p, ok := m.providers[name]; ok {
+		p.cancel()
+		<-p.done
+		delete(m.cache, name)
+	}
+
+	pctx, cancel := context.WithCancel(ctx)
+	p := &provider{
+		name:   name,
+		d:      d,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	m.providers[name] = p
+
+	upCh := make(chan []*targetgroup.Group)
+	go func() {
+		defer close(p.done)
+		d.Run(pctx, upCh)
+	}()
+	go m.debounce(pctx, name, upCh)
+}
+
+// Unregister stops and removes the named provider.
+func (m *Manager) Unregister(name string) {
+	m.mtx.Lock()
+	p, ok := m.providers[name]
+	if ok {
+		delete(m.providers, name)
+		delete(m.cache, name)
+	}
+	m.mtx.Unlock()
+
+	if ok {
+		p.cancel()
+		<-p.done
+	}
+}
+
+// debounce coalesces bursts of updates from a single provider within
+// debounceWindow before handing the latest groups off to the manager loop.
+func (m *Manager) debounce(ctx context.Context, name string, upCh <-chan []*targetgroup.Group) {
+	var (
+		timer   *time.Timer
+		pending []*targetgroup.Group
+		have    bool
+	)
+	for {
+		var fireCh <-chan time.Time
+		if timer != nil {
+			fireCh = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case groups, ok := <-upCh:
+			if !ok {
+				return
+			}
+			pending = groups
+			have = true
+			if timer == nil {
+				timer = time.NewTimer(debounceWindow)
+			} else {
+				timer.Reset(debounceWindow)
+			}
+		case <-fireCh:
+			if have {
+				select {
+				case m.updates <- updateEvent{name: name, groups: pending}:
+					have = false
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+// Run processes coalesced updates from all providers until ctx is cancelled.
+// It must be started once after construction.
+func (m *Manager) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-m.updates:
+			m.apply(ev)
+		}
+	}
+}
+
+func (m *Manager) apply(ev updateEvent) {
+	m.mtx.Lock()
+	groups, ok := m.cache[ev.name]
+	if !ok {
+		groups = make(map[string]*targetgroup.Group)
+		m.cache[ev.name] = groups
+	}
+	for _, tg := range ev.groups {
+		if tg == nil {
+			continue
+		}
+		groups[tg.Source] = tg
+	}
+
+	result := make(map[string][]*targetgroup.Group, len(m.cache))
+	var targets int
+	for name, byName := range m.cache {
+		all := make([]*targetgroup.Group, 0, len(byName))
+		for _, tg := range byName {
+			all = append(all, tg)
+			if name == ev.name {
+				targets += len(tg.Targets)
+			}
+		}
+		result[name] = all
+	}
+	m.mtx.Unlock()
+
+	discoveredTargets.WithLabelValues(ev.name).Set(float64(targets))
+	lastRefreshTimestamp.WithLabelValues(ev.name).Set(float64(time.Now().Unix()))
+
+	m.logger.Debug("discovery manager synced", "provider", ev.name, "groups", len(ev.groups))
+
+	m.send(result)
+}
+
+// send delivers result as the latest state on syncCh, replacing any
+// not-yet-consumed value rather than dropping result when the buffer is
+// already full. apply (and therefore send) only ever runs from Run's single
+// goroutine, so there is never more than one writer racing to fill the slot
+// this frees.
+func (m *Manager) send(result map[string][]*targetgroup.Group) {
+	for {
+		select {
+		case m.syncCh <- result:
+			return
+		default:
+		}
+		select {
+		case <-m.syncCh:
+		default:
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bourbonkk/Clymene/discovery/file"
+	"github.com/bourbonkk/Clymene/discovery/http"
+	"github.com/bourbonkk/Clymene/discovery/targetgroup"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDiscoverer pushes a scripted sequence of updates onto the channel
+// Run is given, then blocks until its context is cancelled.
+type fakeDiscoverer struct {
+	updates [][]*targetgroup.Group
+}
+
+func (f *fakeDiscoverer) Run(ctx context.Context, up chan<- []*targetgroup.Group) {
+	for _, groups := range f.updates {
+		select {
+		case up <- groups:
+		case <-ctx.Done():
+			return
+		}
+	}
+	<-ctx.Done()
+}
+
+func waitForSync(t *testing.T, m *Manager) map[string][]*targetgroup.Group {
+	t.Helper()
+	select {
+	case result := <-m.SyncCh():
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for sync")
+		return nil
+	}
+}
+
+func TestManagerCoalescesBurstsAndDedupsBySource(t *testing.T) {
+	m := NewManager(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	// Two updates for the same Source within the debounce window should
+	// collapse into a single sync carrying only the latest group.
+	d := &fakeDiscoverer{updates: [][]*targetgroup.Group{
+		{{Source: "a", Targets: []model.LabelSet{{"__address__": "1.1.1.1:80"}}}},
+		{{Source: "a", Targets: []model.LabelSet{{"__address__": "2.2.2.2:80"}}}},
+	}}
+	m.Register(ctx, "test", d)
+
+	result := waitForSync(t, m)
+	require.Len(t, result["test"], 1)
+	assert.Equal(t, "a", result["test"][0].Source)
+	assert.Equal(t, []model.LabelSet{{"__address__": "2.2.2.2:80"}}, result["test"][0].Targets)
+}
+
+func TestManagerRegisterUnregisterLifecycle(t *testing.T) {
+	m := NewManager(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	d := &fakeDiscoverer{updates: [][]*targetgroup.Group{
+		{{Source: "a"}},
+	}}
+	m.Register(ctx, "test", d)
+	waitForSync(t, m)
+
+	m.Unregister("test")
+
+	m.mtx.RLock()
+	_, stillRegistered := m.providers["test"]
+	_, stillCached := m.cache["test"]
+	m.mtx.RUnlock()
+	assert.False(t, stillRegistered)
+	assert.False(t, stillCached)
+
+	// Re-registering under the same name should work rather than conflict
+	// with leftover state from the unregistered provider.
+	d2 := &fakeDiscoverer{updates: [][]*targetgroup.Group{
+		{{Source: "b"}},
+	}}
+	m.Register(ctx, "test", d2)
+	result := waitForSync(t, m)
+	require.Len(t, result["test"], 1)
+	assert.Equal(t, "b", result["test"][0].Source)
+}
+
+func TestManagerApplyConfigRegistersFileAndHTTPProviders(t *testing.T) {
+	m := NewManager(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	err := m.ApplyConfig(ctx, Config{
+		File: map[string]*file.SDConfig{
+			"a": {Files: []string{"testdata/does-not-exist-*.json"}, RefreshInterval: model.Duration(time.Minute)},
+		},
+		HTTP: map[string]*http.SDConfig{
+			"b": {URL: "http://example.invalid/targets", RefreshInterval: model.Duration(time.Minute)},
+		},
+	})
+	require.NoError(t, err)
+
+	m.mtx.RLock()
+	_, hasFile := m.providers["file/a"]
+	_, hasHTTP := m.providers["http/b"]
+	m.mtx.RUnlock()
+	assert.True(t, hasFile)
+	assert.True(t, hasHTTP)
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"log/slog"
+)
+
+// Dedup wraps a slog.Handler and suppresses identical records (same
+// level+msg+attrs) seen again within Window, emitting a single "repeated N
+// times" record when the window closes instead of every repeat. This keeps
+// bursty sources - an fsnotify storm, a run of ES bulk errors - from
+// drowning out the rest of the log.
+type Dedup struct {
+	Next   slog.Handler
+	Window time.Duration
+
+	mu      sync.Mutex
+	entries map[uint64]*dedupEntry
+}
+
+type dedupEntry struct {
+	record slog.Record
+	count  int
+	timer  *time.Timer
+}
+
+// Enabled implements slog.Handler.
+func (d *Dedup) Enabled(ctx context.Context, level slog.Level) bool {
+	return d.Next.Enabled(ctx, level)
+}
+
+// WithAttrs implements slog.Handler.
+func (d *Dedup) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Dedup{Next: d.Next.WithAttrs(attrs), Window: d.Window, entries: make(map[uint64]*dedupEntry)}
+}
+
+// WithGroup implements slog.Handler.
+func (d *Dedup) WithGroup(name string) slog.Handler {
+	return &Dedup{Next: d.Next.WithGroup(name), Window: d.Window, entries: make(map[uint64]*dedupEntry)}
+}
+
+// Handle implements slog.Handler. The first occurrence of a record is
+// forwarded immediately; later occurrences within Window are counted and
+// collapsed into a single trailing "repeated N times" record.
+func (d *Dedup) Handle(ctx context.Context, r slog.Record) error {
+	key := hashRecord(r)
+
+	d.mu.Lock()
+	if d.entries == nil {
+		d.entries = make(map[uint64]*dedupEntry)
+	}
+	entry, seen := d.entries[key]
+	if !seen {
+		entry = &dedupEntry{record: r}
+		d.entries[key] = entry
+		entry.timer = time.AfterFunc(d.Window, func() { d.flush(ctx, key) })
+		d.mu.Unlock()
+		return d.Next.Handle(ctx, r)
+	}
+	entry.count++
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *Dedup) flush(ctx context.Context, key uint64) {
+	d.mu.Lock()
+	entry, ok := d.entries[key]
+	if ok {
+		delete(d.entries, key)
+	}
+	d.mu.Unlock()
+	if !ok || entry.count == 0 {
+		return
+	}
+
+	summary := entry.record.Clone()
+	summary.Message = fmt.Sprintf("%s (repeated %d times)", entry.record.Message, entry.count)
+	_ = d.Next.Handle(ctx, summary)
+}
+
+// hashRecord derives a dedup key from level, message and attrs, so that
+// unrelated log lines are never collapsed into one another.
+func hashRecord(r slog.Record) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d|%s", r.Level, r.Message)
+
+	attrs := make([]string, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, fmt.Sprintf("%s=%v", a.Key, a.Value))
+		return true
+	})
+	sort.Strings(attrs)
+	for _, a := range attrs {
+		fmt.Fprintf(h, "|%s", a)
+	}
+	return h.Sum64()
+}
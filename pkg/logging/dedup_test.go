@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupCollapsesRepeatsWithinWindow(t *testing.T) {
+	var buf bytes.Buffer
+	dedup := &Dedup{
+		Next:   slog.NewTextHandler(&buf, nil),
+		Window: 20 * time.Millisecond,
+	}
+	logger := slog.New(dedup)
+
+	for i := 0; i < 5; i++ {
+		logger.Info("fsnotify storm", "path", "/tmp/targets.yml")
+	}
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), "repeated 4 times")
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, 1, strings.Count(buf.String(), `msg="fsnotify storm"`))
+}
+
+func TestDedupKeepsDistinctRecordsSeparate(t *testing.T) {
+	var buf bytes.Buffer
+	dedup := &Dedup{
+		Next:   slog.NewTextHandler(&buf, nil),
+		Window: 20 * time.Millisecond,
+	}
+	logger := slog.New(dedup)
+
+	logger.Info("a")
+	logger.Info("b")
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), "msg=a") && strings.Contains(buf.String(), "msg=b")
+	}, time.Second, time.Millisecond)
+}
+
+func TestDedupHandleFirstRecordReturnsNoError(t *testing.T) {
+	dedup := &Dedup{Next: slog.NewTextHandler(new(bytes.Buffer), nil), Window: time.Millisecond}
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "hello", 0)
+	require.NoError(t, dedup.Handle(context.Background(), r))
+}
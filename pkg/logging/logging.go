@@ -0,0 +1,33 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package logging provides the module-wide structured logger type. Backends
+// (discovery, storage, ...) depend on this package instead of a concrete
+// logging library, so any slog.Handler can back them.
+package logging
+
+import (
+	"io"
+	"log/slog"
+)
+
+// Logger is the structured logger type accepted throughout the module.
+type Logger = slog.Logger
+
+// NewNop returns a Logger that discards everything written to it.
+func NewNop() *Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
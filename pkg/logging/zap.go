@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"go.uber.org/zap"
+)
+
+// FromZap adapts an existing *zap.Logger into a Logger, easing the
+// migration for callers that haven't moved to slog yet. Remove this once
+// all callers construct a Logger directly.
+func FromZap(z *zap.Logger) *Logger {
+	if z == nil {
+		return NewNop()
+	}
+	return slog.New(&zapHandler{z: z})
+}
+
+// zapHandler forwards slog records to a *zap.Logger.
+type zapHandler struct {
+	z *zap.Logger
+}
+
+func (h *zapHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *zapHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := make([]zap.Field, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fields = append(fields, zap.Any(a.Key, a.Value.Any()))
+		return true
+	})
+
+	switch {
+	case r.Level >= slog.LevelError:
+		h.z.Error(r.Message, fields...)
+	case r.Level >= slog.LevelWarn:
+		h.z.Warn(r.Message, fields...)
+	case r.Level >= slog.LevelInfo:
+		h.z.Info(r.Message, fields...)
+	default:
+		h.z.Debug(r.Message, fields...)
+	}
+	return nil
+}
+
+func (h *zapHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make([]zap.Field, 0, len(attrs))
+	for _, a := range attrs {
+		fields = append(fields, zap.Any(a.Key, a.Value.Any()))
+	}
+	return &zapHandler{z: h.z.With(fields...)}
+}
+
+func (h *zapHandler) WithGroup(name string) slog.Handler {
+	return &zapHandler{z: h.z.Named(name)}
+}
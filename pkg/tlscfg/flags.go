@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tlscfg
+
+import (
+	"flag"
+
+	"github.com/spf13/viper"
+)
+
+// ClientFlagsConfig registers the -<prefix>.tls.* flags for a TLS-enabled
+// HTTP client and reads them back from viper into an Options value.
+type ClientFlagsConfig struct {
+	// Prefix is prepended to every flag, e.g. "gateway.http-client".
+	Prefix string
+}
+
+const (
+	suffixEnabled        = ".tls.enabled"
+	suffixCA             = ".tls.ca"
+	suffixCert           = ".tls.cert"
+	suffixKey            = ".tls.key"
+	suffixServerName     = ".tls.server-name"
+	suffixSkipHostVerify = ".tls.skip-host-verify"
+)
+
+// AddFlags registers this configuration's flags on flagSet.
+func (c ClientFlagsConfig) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.Bool(
+		c.Prefix+suffixEnabled,
+		false,
+		"Enable TLS when talking to the remote endpoint",
+	)
+	flagSet.String(
+		c.Prefix+suffixCA,
+		"",
+		"Path to a TLS CA file used to verify the server's certificate",
+	)
+	flagSet.String(
+		c.Prefix+suffixCert,
+		"",
+		"Path to a TLS client certificate, for mTLS",
+	)
+	flagSet.String(
+		c.Prefix+suffixKey,
+		"",
+		"Path to a TLS client key, for mTLS",
+	)
+	flagSet.String(
+		c.Prefix+suffixServerName,
+		"",
+		"Override the expected server name for certificate verification",
+	)
+	flagSet.Bool(
+		c.Prefix+suffixSkipHostVerify,
+		false,
+		"Skip verifying the server's certificate chain and host name",
+	)
+}
+
+// InitFromViper creates Options populated with properties retrieved from Viper.
+func (c ClientFlagsConfig) InitFromViper(v *viper.Viper) Options {
+	return Options{
+		Enabled:        v.GetBool(c.Prefix + suffixEnabled),
+		CAPath:         v.GetString(c.Prefix + suffixCA),
+		CertPath:       v.GetString(c.Prefix + suffixCert),
+		KeyPath:        v.GetString(c.Prefix + suffixKey),
+		ServerName:     v.GetString(c.Prefix + suffixServerName),
+		SkipHostVerify: v.GetBool(c.Prefix + suffixSkipHostVerify),
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tlscfg builds a *tls.Config from file-based CA/cert/key options,
+// reloading the certificate from disk on rotation so callers never need to
+// restart to pick up a renewed certificate.
+package tlscfg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Options describes the TLS settings for an HTTP client: whether TLS is
+// enabled at all, the CA used to verify the server, and an optional client
+// certificate for mTLS.
+type Options struct {
+	Enabled        bool
+	CAPath         string
+	CertPath       string
+	KeyPath        string
+	ServerName     string
+	SkipHostVerify bool
+}
+
+// Config builds a *tls.Config from the Options. When CertPath/KeyPath are
+// set, the client certificate is loaded through a certReloader so a renewed
+// certificate written to the same path is picked up on the next handshake
+// without a process restart.
+func (o Options) Config() (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.SkipHostVerify,
+	}
+
+	if o.CAPath != "" {
+		pool, err := loadCertPool(o.CAPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load CA file")
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if o.CertPath != "" || o.KeyPath != "" {
+		reloader, err := newCertReloader(o.CertPath, o.KeyPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load client certificate")
+		}
+		tlsCfg.GetClientCertificate = reloader.getClientCertificate
+	}
+
+	return tlsCfg, nil
+}
+
+func loadCertPool(caPath string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(caPath)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.Errorf("failed to parse CA file %q", caPath)
+	}
+	return pool, nil
+}
+
+// certReloader keeps the most recently loaded client certificate in memory
+// and re-reads cert/key from disk whenever their modification time changes,
+// so a rotated certificate is honored without restarting the process.
+type certReloader struct {
+	certPath string
+	keyPath  string
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+func newCertReloader(certPath, keyPath string) (*certReloader, error) {
+	r := &certReloader{certPath: certPath, keyPath: keyPath}
+	if _, err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) load() (*tls.Certificate, error) {
+	certInfo, err := os.Stat(r.certPath)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo, err := os.Stat(r.keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if r.cert != nil && certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime) {
+		cert := r.cert
+		r.mu.Unlock()
+		return cert, nil
+	}
+	r.mu.Unlock()
+
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	r.mu.Unlock()
+	return &cert, nil
+}
+
+// getClientCertificate is called by crypto/tls on every handshake, so a
+// reload here is picked up by the very next connection.
+func (r *certReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if cert, err := r.load(); err == nil {
+		return cert, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cert != nil {
+		return r.cert, nil
+	}
+	return nil, errors.Errorf("no usable client certificate for %q", r.certPath)
+}
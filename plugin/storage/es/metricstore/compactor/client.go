@@ -0,0 +1,158 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compactor
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	elastic "github.com/olivere/elastic/v7"
+	"github.com/pkg/errors"
+)
+
+// Client is the narrow slice of Elasticsearch operations the compactor
+// needs: deleting aged-out documents, rolling raw samples up into a second
+// index, and taking the lease that keeps multiple replicas from compacting
+// concurrently.
+type Client interface {
+	// DeleteByAge deletes documents in index whose timestampField is older
+	// than olderThan, returning the number of documents deleted.
+	DeleteByAge(ctx context.Context, index, timestampField string, olderThan time.Time) (int64, error)
+	// Rollup aggregates documents in srcIndex older than olderThan into
+	// bucketed documents written to dstIndex.
+	Rollup(ctx context.Context, srcIndex, dstIndex, timestampField string, olderThan time.Time, bucket time.Duration) error
+	// AcquireLease attempts to take the compactor lease document in
+	// leaseIndex for ttl, recording name as its holder, and returns true if
+	// this replica now holds it.
+	AcquireLease(ctx context.Context, leaseIndex, name string, ttl time.Duration) (bool, error)
+}
+
+// esClient adapts a real *elastic.Client to the Client interface above.
+type esClient struct {
+	es *elastic.Client
+}
+
+// NewClient wraps an *elastic.Client for use by the compactor.
+func NewClient(es *elastic.Client) Client {
+	return &esClient{es: es}
+}
+
+func (c *esClient) DeleteByAge(ctx context.Context, index, timestampField string, olderThan time.Time) (int64, error) {
+	query := elastic.NewRangeQuery(timestampField).Lt(olderThan.UnixMilli())
+	resp, err := c.es.DeleteByQuery(index).Query(query).Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Deleted, nil
+}
+
+func (c *esClient) Rollup(ctx context.Context, srcIndex, dstIndex, timestampField string, olderThan time.Time, bucket time.Duration) error {
+	agg := elastic.NewDateHistogramAggregation().
+		Field(timestampField).
+		FixedInterval(bucket.String()).
+		SubAggregation("avg_value", elastic.NewAvgAggregation().Field("value")).
+		SubAggregation("sum_value", elastic.NewSumAggregation().Field("value"))
+
+	query := elastic.NewRangeQuery(timestampField).Lt(olderThan.UnixMilli())
+
+	result, err := c.es.Search(srcIndex).Query(query).Size(0).Aggregation("buckets", agg).Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	buckets, found := result.Aggregations.DateHistogram("buckets")
+	if !found {
+		return nil
+	}
+
+	bulk := c.es.Bulk()
+	for _, b := range buckets.Buckets {
+		avg, _ := b.Avg("avg_value")
+		sum, _ := b.Sum("sum_value")
+		doc := map[string]interface{}{
+			"@timestamp": b.KeyAsString,
+			"count":      b.DocCount,
+		}
+		if avg != nil {
+			doc["avg"] = avg.Value
+		}
+		if sum != nil {
+			doc["sum"] = sum.Value
+		}
+		bulk.Add(elastic.NewBulkIndexRequest().Index(dstIndex).Doc(doc))
+	}
+	if bulk.NumberOfActions() == 0 {
+		return nil
+	}
+	_, err = bulk.Do(ctx)
+	return err
+}
+
+// leaseDoc is the document body used to hold a compactor lease.
+type leaseDoc struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (c *esClient) AcquireLease(ctx context.Context, leaseIndex, name string, ttl time.Duration) (bool, error) {
+	doc := leaseDoc{Holder: name, ExpiresAt: time.Now().Add(ttl)}
+
+	// A plain Create succeeds only if no lease document exists yet, which is
+	// the common case for the very first replica to start.
+	_, err := c.es.Index().Index(leaseIndex).Id(leaseName).OpType("create").BodyJson(doc).Do(ctx)
+	if err == nil {
+		return true, nil
+	}
+	if !elastic.IsConflict(err) {
+		return false, err
+	}
+
+	// Someone already holds the lease; only take over if it has expired.
+	existing, err := c.es.Get().Index(leaseIndex).Id(leaseName).Do(ctx)
+	if err != nil {
+		return false, err
+	}
+	var current leaseDoc
+	if err := json.Unmarshal(existing.Source, &current); err != nil {
+		return false, err
+	}
+	if time.Now().Before(current.ExpiresAt) {
+		return false, nil
+	}
+
+	if existing.SeqNo == nil || existing.PrimaryTerm == nil {
+		return false, errors.New("compactor lease get response missing seq_no/primary_term")
+	}
+
+	// The take-over write is conditioned on the document's seq_no/primary_term
+	// still matching what we just read. If another replica already took over
+	// in between our Get and this Index, ES rejects it as a conflict and we
+	// report the lease as lost rather than winning it a second time.
+	_, err = c.es.Index().Index(leaseIndex).Id(leaseName).
+		IfSeqNo(*existing.SeqNo).IfPrimaryTerm(*existing.PrimaryTerm).
+		BodyJson(doc).Do(ctx)
+	if err != nil {
+		if elastic.IsConflict(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+const leaseName = "compactor-lease"
@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package compactor periodically deletes or rolls up ES metric documents
+// older than a configured retention window, running alongside MetricWriter.
+package compactor
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"go.uber.org/zap"
+)
+
+const (
+	// jitterFraction caps the random jitter added to each tick at this
+	// fraction of Options.Interval, to avoid every replica hitting ES at
+	// exactly the same moment.
+	jitterFraction = 0.1
+	leaseIndex     = "clymene-metrics-compactor-lease"
+	leaseTTL       = 5 * time.Minute
+	timestampField = "@timestamp"
+	rollupBucket   = 5 * time.Minute
+)
+
+// Compactor deletes or rolls up documents in index older than Options.Retention,
+// on a jittered Options.Interval tick.
+type Compactor struct {
+	client Client
+	opts   Options
+	index  string
+	clock  clockwork.Clock
+	logger *zap.Logger
+	name   string
+}
+
+// New creates a Compactor for index using opts. name identifies this
+// replica when acquiring the compaction lease.
+func New(client Client, opts Options, index, name string, logger *zap.Logger) *Compactor {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Compactor{
+		client: client,
+		opts:   opts,
+		index:  index,
+		clock:  clockwork.NewRealClock(),
+		logger: logger,
+		name:   name,
+	}
+}
+
+// WithClock overrides the Compactor's clock, for testing.
+func (c *Compactor) WithClock(clock clockwork.Clock) *Compactor {
+	c.clock = clock
+	return c
+}
+
+// Run ticks every Options.Interval (plus jitter) until ctx is cancelled,
+// running a compaction pass on each tick.
+func (c *Compactor) Run(ctx context.Context) {
+	for {
+		wait := jitter(c.opts.Interval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.clock.After(wait):
+		}
+		c.runOnce(ctx)
+	}
+}
+
+func jitter(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	delta := time.Duration(rand.Float64() * jitterFraction * float64(interval))
+	return interval + delta
+}
+
+// runOnce performs a single compaction pass, guarded by the ES-backed lease
+// so only one replica compacts at a time.
+func (c *Compactor) runOnce(ctx context.Context) {
+	held, err := c.client.AcquireLease(ctx, leaseIndex, c.name, leaseTTL)
+	if err != nil {
+		c.logger.Error("failed to acquire compactor lease", zap.Error(err))
+		errorsTotal.Inc()
+		return
+	}
+	if !held {
+		return
+	}
+
+	start := c.clock.Now()
+	runsTotal.Inc()
+
+	cutoff := start.Add(-c.opts.Retention)
+
+	var err2 error
+	switch c.opts.Mode {
+	case ModeRollup:
+		err2 = c.rollup(ctx, cutoff)
+	default:
+		err2 = c.periodic(ctx, cutoff)
+	}
+
+	lastDurationSeconds.Set(c.clock.Now().Sub(start).Seconds())
+	if err2 != nil {
+		errorsTotal.Inc()
+		c.logger.Error("compactor run failed", zap.String("mode", c.opts.Mode), zap.Error(err2))
+	}
+}
+
+func (c *Compactor) periodic(ctx context.Context, cutoff time.Time) error {
+	deleted, err := c.client.DeleteByAge(ctx, c.index, timestampField, cutoff)
+	if err != nil {
+		return err
+	}
+	deletedDocsTotal.Add(float64(deleted))
+	c.logger.Debug("compactor deleted aged-out documents", zap.String("index", c.index), zap.Int64("deleted", deleted))
+	return nil
+}
+
+func (c *Compactor) rollup(ctx context.Context, cutoff time.Time) error {
+	rollupIndex := c.index + "-rollup"
+	if err := c.client.Rollup(ctx, c.index, rollupIndex, timestampField, cutoff, rollupBucket); err != nil {
+		return err
+	}
+	return c.periodic(ctx, cutoff)
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compactor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClient struct {
+	deleted     int64
+	deleteCalls int
+	rollupCalls int
+	leaseHeld   bool
+	leaseErr    error
+	deleteErr   error
+}
+
+func (f *fakeClient) DeleteByAge(ctx context.Context, index, timestampField string, olderThan time.Time) (int64, error) {
+	f.deleteCalls++
+	return f.deleted, f.deleteErr
+}
+
+func (f *fakeClient) Rollup(ctx context.Context, srcIndex, dstIndex, timestampField string, olderThan time.Time, bucket time.Duration) error {
+	f.rollupCalls++
+	return nil
+}
+
+func (f *fakeClient) AcquireLease(ctx context.Context, leaseIndex, name string, ttl time.Duration) (bool, error) {
+	return f.leaseHeld, f.leaseErr
+}
+
+func TestCompactorPeriodicDeletesWhenLeaseHeld(t *testing.T) {
+	client := &fakeClient{deleted: 42, leaseHeld: true}
+	c := New(client, Options{Mode: ModePeriodic, Retention: time.Hour, Interval: time.Minute}, "clymene-metrics", "replica-a", nil)
+
+	c.runOnce(context.Background())
+
+	assert.Equal(t, 1, client.deleteCalls)
+	assert.Equal(t, 0, client.rollupCalls)
+}
+
+func TestCompactorSkipsRunWithoutLease(t *testing.T) {
+	client := &fakeClient{leaseHeld: false}
+	c := New(client, Options{Mode: ModePeriodic, Retention: time.Hour, Interval: time.Minute}, "clymene-metrics", "replica-b", nil)
+
+	c.runOnce(context.Background())
+
+	assert.Equal(t, 0, client.deleteCalls)
+}
+
+func TestCompactorRollupModeRollsUpThenDeletes(t *testing.T) {
+	client := &fakeClient{leaseHeld: true}
+	c := New(client, Options{Mode: ModeRollup, Retention: time.Hour, Interval: time.Minute}, "clymene-metrics", "replica-a", nil)
+
+	c.runOnce(context.Background())
+
+	assert.Equal(t, 1, client.rollupCalls)
+	assert.Equal(t, 1, client.deleteCalls)
+}
+
+func TestCompactorRunTicksOnFakeClock(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	client := &fakeClient{leaseHeld: true}
+	c := New(client, Options{Mode: ModePeriodic, Retention: time.Hour, Interval: time.Minute}, "clymene-metrics", "replica-a", nil).
+		WithClock(clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(2 * time.Minute)
+
+	require.Eventually(t, func() bool {
+		return client.deleteCalls >= 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
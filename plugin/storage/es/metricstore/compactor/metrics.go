@@ -0,0 +1,42 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compactor
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	runsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "clymene_es_compactor_runs_total",
+		Help: "Total number of compactor runs.",
+	})
+	errorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "clymene_es_compactor_errors_total",
+		Help: "Total number of compactor errors, including failed lease acquisitions.",
+	})
+	lastDurationSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "clymene_es_compactor_last_duration_seconds",
+		Help: "Duration of the most recent compactor run, in seconds.",
+	})
+	deletedDocsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "clymene_es_compactor_deleted_docs_total",
+		Help: "Total number of documents deleted by the compactor.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(runsTotal, errorsTotal, lastDurationSeconds, deletedDocsTotal)
+}
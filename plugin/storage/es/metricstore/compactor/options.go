@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compactor
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// ModePeriodic deletes documents older than Retention.
+	ModePeriodic = "periodic"
+	// ModeRollup aggregates raw samples older than Retention into 5m
+	// buckets in a second index, then deletes the originals. No 1h buckets
+	// are produced.
+	ModeRollup = "rollup"
+)
+
+const (
+	compactorPrefix = "es.compactor"
+	suffixMode      = ".mode"
+	suffixRetention = ".retention"
+	suffixInterval  = ".interval"
+
+	defaultMode      = ModePeriodic
+	defaultRetention = 30 * 24 * time.Hour
+	defaultInterval  = time.Hour
+)
+
+// Options configures the retention compactor.
+type Options struct {
+	Mode      string
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+// AddFlags adds flags for Options.
+func AddFlags(flagSet *flag.FlagSet) {
+	flagSet.String(
+		compactorPrefix+suffixMode,
+		defaultMode,
+		"Compaction mode: 'periodic' deletes documents past the retention window, 'rollup' aggregates them into 5m buckets before deleting",
+	)
+	flagSet.Duration(
+		compactorPrefix+suffixRetention,
+		defaultRetention,
+		"How long metric documents are kept before being compacted",
+	)
+	flagSet.Duration(
+		compactorPrefix+suffixInterval,
+		defaultInterval,
+		"How often the compactor checks for documents to compact",
+	)
+}
+
+// InitFromViper initializes Options with properties retrieved from Viper.
+func (o *Options) InitFromViper(v *viper.Viper) {
+	o.Mode = v.GetString(compactorPrefix + suffixMode)
+	o.Retention = v.GetDuration(compactorPrefix + suffixRetention)
+	o.Interval = v.GetDuration(compactorPrefix + suffixInterval)
+}
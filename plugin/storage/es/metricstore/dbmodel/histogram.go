@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbmodel
+
+import (
+	"github.com/Clymene-project/Clymene/prompb"
+)
+
+// HistogramSpan mirrors prompb.BucketSpan for JSON (de)serialization, since
+// the wire type isn't tagged for encoding/json.
+type HistogramSpan struct {
+	Offset int32  `json:"offset"`
+	Length uint32 `json:"length"`
+}
+
+// Histogram is the ES document shape for a Prometheus native (sparse)
+// histogram sample. Bucket spans/deltas are kept as arrays rather than
+// exploded into per-bucket fields, so the mapping template can map them as
+// `enabled: false` and avoid field-count blowups on high-resolution buckets.
+type Histogram struct {
+	Schema         int32           `json:"schema"`
+	ZeroThreshold  float64         `json:"zero_threshold"`
+	ZeroCount      float64         `json:"zero_count"`
+	Count          float64         `json:"count"`
+	Sum            float64         `json:"sum"`
+	NegativeSpans  []HistogramSpan `json:"negative_spans,omitempty"`
+	NegativeDeltas []int64         `json:"negative_deltas,omitempty"`
+	PositiveSpans  []HistogramSpan `json:"positive_spans,omitempty"`
+	PositiveDeltas []int64         `json:"positive_deltas,omitempty"`
+}
+
+// ConvertHistogramToJSON converts a time series carrying native histogram
+// samples into one ES document per histogram sample, with labels flattened
+// alongside the encoded histogram the same way ConvertTsToJSON does for
+// plain float samples.
+func (Converter) ConvertHistogramToJSON(ts prompb.TimeSeries) []map[string]interface{} {
+	docs := make([]map[string]interface{}, 0, len(ts.Histograms))
+	for _, h := range ts.Histograms {
+		doc := make(map[string]interface{}, len(ts.Labels)+2)
+		for _, l := range ts.Labels {
+			doc[l.Name] = l.Value
+		}
+		doc["@timestamp"] = h.Timestamp
+		doc["histogram"] = toHistogramDoc(h)
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func toHistogramDoc(h prompb.Histogram) Histogram {
+	return Histogram{
+		Schema:         h.Schema,
+		ZeroThreshold:  h.ZeroThreshold,
+		ZeroCount:      histogramCount(h.ZeroCount),
+		Count:          histogramCount(h.Count),
+		Sum:            h.Sum,
+		NegativeSpans:  toSpans(h.NegativeSpans),
+		NegativeDeltas: h.NegativeDeltas,
+		PositiveSpans:  toSpans(h.PositiveSpans),
+		PositiveDeltas: h.PositiveDeltas,
+	}
+}
+
+// histogramCount extracts the numeric value out of a Count/ZeroCount oneof.
+// Delta-encoded native histograms - the common case - carry these as the
+// *_Int arm; only float histograms use the *_Float arm. Using only
+// GetXFloat() would silently read 0 for every integer-count sample.
+func histogramCount(count interface{}) float64 {
+	switch c := count.(type) {
+	case *prompb.Histogram_CountInt:
+		return float64(c.CountInt)
+	case *prompb.Histogram_CountFloat:
+		return c.CountFloat
+	case *prompb.Histogram_ZeroCountInt:
+		return float64(c.ZeroCountInt)
+	case *prompb.Histogram_ZeroCountFloat:
+		return c.ZeroCountFloat
+	default:
+		return 0
+	}
+}
+
+func toSpans(spans []prompb.BucketSpan) []HistogramSpan {
+	if len(spans) == 0 {
+		return nil
+	}
+	out := make([]HistogramSpan, len(spans))
+	for i, s := range spans {
+		out[i] = HistogramSpan{Offset: s.Offset, Length: s.Length}
+	}
+	return out
+}
+
+// DecodeHistogram reconstructs a Histogram from the raw ES document fields,
+// for read paths that query the histogram index directly.
+func DecodeHistogram(doc map[string]interface{}) (Histogram, bool) {
+	raw, ok := doc["histogram"]
+	if !ok {
+		return Histogram{}, false
+	}
+	h, ok := raw.(map[string]interface{})
+	if !ok {
+		return Histogram{}, false
+	}
+
+	var out Histogram
+	out.Schema = int32(asFloat(h["schema"]))
+	out.ZeroThreshold = asFloat(h["zero_threshold"])
+	out.ZeroCount = asFloat(h["zero_count"])
+	out.Count = asFloat(h["count"])
+	out.Sum = asFloat(h["sum"])
+	out.NegativeSpans = asSpans(h["negative_spans"])
+	out.NegativeDeltas = asDeltas(h["negative_deltas"])
+	out.PositiveSpans = asSpans(h["positive_spans"])
+	out.PositiveDeltas = asDeltas(h["positive_deltas"])
+	return out, true
+}
+
+func asFloat(v interface{}) float64 {
+	f, _ := v.(float64)
+	return f
+}
+
+func asSpans(v interface{}) []HistogramSpan {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	spans := make([]HistogramSpan, 0, len(list))
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		spans = append(spans, HistogramSpan{
+			Offset: int32(asFloat(m["offset"])),
+			Length: uint32(asFloat(m["length"])),
+		})
+	}
+	return spans
+}
+
+func asDeltas(v interface{}) []int64 {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	deltas := make([]int64, 0, len(list))
+	for _, item := range list {
+		deltas = append(deltas, int64(asFloat(item)))
+	}
+	return deltas
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbmodel
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Clymene-project/Clymene/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertHistogramToJSON(t *testing.T) {
+	ts := prompb.TimeSeries{
+		Labels: []prompb.Label{{Name: "__name__", Value: "http_request_duration_seconds"}},
+		Histograms: []prompb.Histogram{
+			{
+				Timestamp:      1000,
+				Schema:         3,
+				ZeroThreshold:  0.001,
+				Sum:            12.5,
+				PositiveSpans:  []prompb.BucketSpan{{Offset: 0, Length: 2}},
+				PositiveDeltas: []int64{1, 1},
+				NegativeSpans:  []prompb.BucketSpan{{Offset: 0, Length: 1}},
+				NegativeDeltas: []int64{1},
+			},
+		},
+	}
+
+	c := Converter{}
+	docs := c.ConvertHistogramToJSON(ts)
+
+	assert.Len(t, docs, 1)
+	doc := docs[0]
+	assert.Equal(t, "http_request_duration_seconds", doc["__name__"])
+	assert.Equal(t, int64(1000), doc["@timestamp"])
+
+	// Round-trip through JSON the way the ES client would, so DecodeHistogram
+	// sees the same shape it would when reading a stored document back.
+	raw, err := json.Marshal(doc)
+	require.NoError(t, err)
+	var stored map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &stored))
+
+	decoded, ok := DecodeHistogram(stored)
+	assert.True(t, ok)
+	assert.Equal(t, int32(3), decoded.Schema)
+	assert.Equal(t, 12.5, decoded.Sum)
+	assert.Equal(t, []HistogramSpan{{Offset: 0, Length: 2}}, decoded.PositiveSpans)
+	assert.Equal(t, []int64{1, 1}, decoded.PositiveDeltas)
+}
+
+func TestConvertHistogramToJSONIntegerCount(t *testing.T) {
+	ts := prompb.TimeSeries{
+		Labels: []prompb.Label{{Name: "__name__", Value: "http_request_duration_seconds"}},
+		Histograms: []prompb.Histogram{
+			{
+				Timestamp: 1000,
+				Schema:    3,
+				Count:     &prompb.Histogram_CountInt{CountInt: 42},
+				ZeroCount: &prompb.Histogram_ZeroCountInt{ZeroCountInt: 7},
+			},
+		},
+	}
+
+	c := Converter{}
+	docs := c.ConvertHistogramToJSON(ts)
+	require.Len(t, docs, 1)
+
+	raw, err := json.Marshal(docs[0])
+	require.NoError(t, err)
+	var stored map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &stored))
+
+	decoded, ok := DecodeHistogram(stored)
+	assert.True(t, ok)
+	// Regression: histogramCount used to only read GetXFloat(), which
+	// silently zeroed delta-encoded integer counts - the common case.
+	assert.Equal(t, float64(42), decoded.Count)
+	assert.Equal(t, float64(7), decoded.ZeroCount)
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbmodel
+
+// HistogramIndexSuffix is appended to the configured index prefix for
+// documents produced by ConvertHistogramToJSON, keeping native histogram
+// samples out of the plain-sample index.
+const HistogramIndexSuffix = "clymene-metrics-histograms"
+
+// HistogramIndexMapping is an index template body, keyed to the histogram
+// index name via index_patterns, that maps the histogram document's bucket
+// span/delta arrays as disabled, so ES stores them as opaque arrays instead
+// of exploding each bucket into its own indexed field. %q is replaced with
+// the concrete (possibly prefixed) histogram index name.
+const HistogramIndexMapping = `{
+  "index_patterns": [%q],
+  "mappings": {
+    "properties": {
+      "@timestamp": { "type": "date" },
+      "histogram": {
+        "properties": {
+          "schema": { "type": "integer" },
+          "zero_threshold": { "type": "double" },
+          "zero_count": { "type": "double" },
+          "count": { "type": "double" },
+          "sum": { "type": "double" },
+          "negative_spans": { "enabled": false },
+          "negative_deltas": { "enabled": false },
+          "positive_spans": { "enabled": false },
+          "positive_deltas": { "enabled": false }
+        }
+      }
+    }
+  }
+}`
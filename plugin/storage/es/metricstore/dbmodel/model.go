@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package dbmodel converts prompb.TimeSeries samples into the JSON documents
+// written to and read back from Elasticsearch.
+package dbmodel
+
+import (
+	"github.com/Clymene-project/Clymene/prompb"
+)
+
+// Converter turns prompb wire types into the flat JSON documents indexed in
+// Elasticsearch, and back.
+type Converter struct{}
+
+// ConvertTsToJSON converts a single-sample time series into an ES document:
+// one document per sample, with labels flattened alongside value/timestamp.
+func (Converter) ConvertTsToJSON(ts prompb.TimeSeries) map[string]interface{} {
+	doc := make(map[string]interface{}, len(ts.Labels)+2)
+	for _, l := range ts.Labels {
+		doc[l.Name] = l.Value
+	}
+	for _, s := range ts.Samples {
+		doc["@timestamp"] = s.Timestamp
+		doc["value"] = s.Value
+	}
+	return doc
+}
@@ -17,7 +17,12 @@
 package es
 
 import (
+	"context"
+	"fmt"
+	"sync"
+
 	"github.com/Clymene-project/Clymene/pkg/es"
+	"github.com/Clymene-project/Clymene/plugin/storage/es/metricstore/compactor"
 	"github.com/Clymene-project/Clymene/plugin/storage/es/metricstore/dbmodel"
 	"github.com/Clymene-project/Clymene/prompb"
 	"go.uber.org/zap"
@@ -29,10 +34,16 @@ const (
 )
 
 type MetricWriter struct {
-	client      es.Client
-	logger      *zap.Logger
-	metricIndex string
-	converter   dbmodel.Converter
+	client         es.Client
+	logger         *zap.Logger
+	metricIndex    string
+	histogramIndex string
+	converter      dbmodel.Converter
+
+	histogramTemplateMu   sync.Mutex
+	histogramTemplateDone bool
+
+	compactorCancel context.CancelFunc
 }
 
 // MetricWriterParams holds constructor parameters for NewMetricWriter
@@ -41,6 +52,10 @@ type MetricWriterParams struct {
 	Logger      *zap.Logger
 	IndexPrefix string
 	Archive     bool
+	// Compactor, if set, is started alongside the writer to enforce
+	// Compactor's configured retention against the metric index. Leave nil
+	// to disable retention compaction.
+	Compactor *compactor.Compactor
 }
 
 // NewMetricWriter creates a new MetricWriter for use
@@ -49,23 +64,75 @@ func NewMetricWriter(p MetricWriterParams) *MetricWriter {
 	if p.IndexPrefix != "" {
 		prefix = p.IndexPrefix + "-"
 	}
-	return &MetricWriter{
-		client:      p.Client,
-		logger:      p.Logger,
-		metricIndex: prefix + clymeneIndex,
-		converter:   dbmodel.Converter{},
+	w := &MetricWriter{
+		client:         p.Client,
+		logger:         p.Logger,
+		metricIndex:    prefix + clymeneIndex,
+		histogramIndex: prefix + dbmodel.HistogramIndexSuffix,
+		converter:      dbmodel.Converter{},
+	}
+	if p.Compactor != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		w.compactorCancel = cancel
+		go p.Compactor.Run(ctx)
+	}
+	return w
+}
+
+// Close stops the background compactor started alongside this writer, if
+// one was configured.
+func (s *MetricWriter) Close() {
+	if s.compactorCancel != nil {
+		s.compactorCancel()
 	}
 }
 
 func (s *MetricWriter) WriteMetric(metrics []prompb.TimeSeries) error {
 	for _, metric := range metrics {
-		jsonTimeSeries := s.converter.ConvertTsToJSON(metric)
-		s.writeMetric(&jsonTimeSeries)
+		if len(metric.Histograms) > 0 {
+			if err := s.ensureHistogramTemplate(); err != nil {
+				s.logger.Error("failed to ensure histogram index template", zap.Error(err))
+			}
+			for _, doc := range s.converter.ConvertHistogramToJSON(metric) {
+				s.writeHistogram(&doc)
+			}
+		}
+		if len(metric.Samples) > 0 {
+			jsonTimeSeries := s.converter.ConvertTsToJSON(metric)
+			s.writeMetric(&jsonTimeSeries)
+		}
 	}
 	return nil
 }
 
+// ensureHistogramTemplate installs HistogramIndexMapping as an index
+// template matching histogramIndex, so the bucket span/delta fields are
+// mapped `enabled: false` from the first write onward instead of being
+// dynamically mapped by ES as indexed objects. Installation is retried on
+// every call until it succeeds, since a transient ES error must not
+// permanently disable the template for the rest of the process's life.
+func (s *MetricWriter) ensureHistogramTemplate() error {
+	s.histogramTemplateMu.Lock()
+	defer s.histogramTemplateMu.Unlock()
+	if s.histogramTemplateDone {
+		return nil
+	}
+	body := fmt.Sprintf(dbmodel.HistogramIndexMapping, s.histogramIndex)
+	if _, err := s.client.CreateTemplate(s.histogramIndex).Body(body).Do(context.Background()); err != nil {
+		return err
+	}
+	s.histogramTemplateDone = true
+	return nil
+}
+
 // bulk insert
 func (s *MetricWriter) writeMetric(metric *map[string]interface{}) {
 	s.client.Index().Index(s.metricIndex).Type(metricType).BodyJson(&metric).Add()
+}
+
+// bulk insert of native (sparse) histogram documents, kept in their own
+// index so the bucket span/delta arrays don't get exploded as objects
+// alongside the flat float-sample mapping.
+func (s *MetricWriter) writeHistogram(doc *map[string]interface{}) {
+	s.client.Index().Index(s.histogramIndex).Type(metricType).BodyJson(&doc).Add()
 }
\ No newline at end of file
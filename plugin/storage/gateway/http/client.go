@@ -0,0 +1,203 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+)
+
+// buildHTTPClient constructs the *http.Client shared by the metrics and logs
+// RemoteWriteClients, enabling TLS (including mTLS) when o.TLS.Enabled.
+func buildHTTPClient(o *Options) (*http.Client, error) {
+	client := &http.Client{Timeout: o.timeout}
+	if !o.TLS.Enabled {
+		return client, nil
+	}
+	tlsCfg, err := o.TLS.Config()
+	if err != nil {
+		return nil, errors.Wrap(err, "building gateway http-client TLS config")
+	}
+	client.Transport = &http.Transport{TLSClientConfig: tlsCfg}
+	return client, nil
+}
+
+const (
+	remoteWriteVersionHeader = "0.1.0"
+)
+
+// Codec encodes a payload (a prompb.WriteRequest for metrics, a loki push
+// request for logs) into the wire bytes to send, along with the Content-Type
+// to advertise for it. Metrics and logs share the retrying RemoteWriteClient
+// below by each supplying their own Codec.
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+}
+
+// remoteWriteVersioner is implemented by Codecs that speak the Prometheus
+// remote_write wire contract, so send() only sets
+// X-Prometheus-Remote-Write-Version for those and not for e.g. LokiCodec.
+type remoteWriteVersioner interface {
+	RemoteWriteVersion() string
+}
+
+// RemoteWriteClient sends snappy-compressed protobuf payloads to a
+// Prometheus remote_write-compatible HTTP receiver, retrying 5xx/429
+// responses with exponential backoff and treating 4xx as permanent.
+type RemoteWriteClient struct {
+	url          string
+	client       *http.Client
+	userAgent    string
+	retry        RetryOptions
+	codec        Codec
+	maxErrMsgLen int64
+}
+
+// NewRemoteWriteClient builds a RemoteWriteClient that posts to url using the
+// given codec to marshal payloads. Both the metrics and logs URLs share this
+// constructor, so TLS/mTLS configured via Options.TLS applies to both.
+func NewRemoteWriteClient(url string, o *Options, codec Codec) (*RemoteWriteClient, error) {
+	client, err := buildHTTPClient(o)
+	if err != nil {
+		return nil, err
+	}
+	maxErrMsgLen := o.maxErrMsgLen
+	if maxErrMsgLen <= 0 {
+		maxErrMsgLen = defaultMaxErrMsgLen
+	}
+	return &RemoteWriteClient{
+		url:          url,
+		client:       client,
+		userAgent:    o.userAgent,
+		retry:        o.Retry,
+		codec:        codec,
+		maxErrMsgLen: maxErrMsgLen,
+	}, nil
+}
+
+// Store marshals v with the client's codec, snappy-compresses it, and posts
+// it to the configured URL, retrying transient failures with exponential
+// backoff.
+func (c *RemoteWriteClient) Store(ctx context.Context, v interface{}) error {
+	raw, err := c.codec.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "encoding remote write payload")
+	}
+	compressed := snappy.Encode(nil, raw)
+
+	backoff := c.retry.MinBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryMinBackoff
+	}
+	maxAttempts := c.retry.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultRetryMaxAttempts
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		retryAfter, err := c.send(ctx, compressed)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !isRetryable(err) {
+			return err
+		}
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+		if c.retry.MaxBackoff > 0 && backoff > c.retry.MaxBackoff {
+			backoff = c.retry.MaxBackoff
+		}
+	}
+	return errors.Wrapf(lastErr, "remote write failed after %d attempts", maxAttempts)
+}
+
+// permanentError wraps an error that Store must not retry: a 4xx response
+// other than 429, or a request that could not be built.
+type permanentError struct{ error }
+
+func isRetryable(err error) bool {
+	_, permanent := errors.Cause(err).(permanentError)
+	return !permanent
+}
+
+// send issues a single POST of the already-compressed body, returning the
+// server's Retry-After duration (if any) alongside a retryable/permanent
+// error.
+func (c *RemoteWriteClient) send(ctx context.Context, body []byte) (time.Duration, error) {
+	req, err := http.NewRequest("POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, permanentError{err}
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", c.codec.ContentType())
+	if v, ok := c.codec.(remoteWriteVersioner); ok {
+		req.Header.Set("X-Prometheus-Remote-Write-Version", v.RemoteWriteVersion())
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return 0, nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, c.maxErrMsgLen))
+	httpErr := errors.Errorf("remote write returned HTTP status %s: %s", resp.Status, string(msg))
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5 {
+		return parseRetryAfter(resp.Header.Get("Retry-After")), httpErr
+	}
+	return 0, permanentError{httpErr}
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
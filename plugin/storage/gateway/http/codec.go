@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"github.com/Clymene-project/Clymene/pkg/logproto"
+	"github.com/Clymene-project/Clymene/prompb"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// PrompbCodec marshals prompb.WriteRequest payloads for the metrics
+// RemoteWriteClient.
+type PrompbCodec struct{}
+
+func (PrompbCodec) ContentType() string { return "application/x-protobuf" }
+
+// RemoteWriteVersion implements remoteWriteVersioner, advertising the
+// Prometheus remote_write wire contract this codec encodes to.
+func (PrompbCodec) RemoteWriteVersion() string { return remoteWriteVersionHeader }
+
+func (PrompbCodec) Marshal(v interface{}) ([]byte, error) {
+	req, ok := v.(*prompb.WriteRequest)
+	if !ok {
+		return nil, errors.Errorf("PrompbCodec: unexpected payload type %T", v)
+	}
+	return proto.Marshal(req)
+}
+
+// LokiCodec marshals logproto.PushRequest payloads so the logs URL can reuse
+// the same retrying RemoteWriteClient as metrics.
+type LokiCodec struct{}
+
+func (LokiCodec) ContentType() string { return "application/x-protobuf" }
+
+func (LokiCodec) Marshal(v interface{}) ([]byte, error) {
+	req, ok := v.(*logproto.PushRequest)
+	if !ok {
+		return nil, errors.Errorf("LokiCodec: unexpected payload type %T", v)
+	}
+	return proto.Marshal(req)
+}
@@ -0,0 +1,28 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var droppedSamplesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "clymene_gateway_http_dropped_samples_total",
+	Help: "Total number of samples dropped because a remote write batch send failed.",
+})
+
+func init() {
+	prometheus.MustRegister(droppedSamplesTotal)
+}
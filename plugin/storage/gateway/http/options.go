@@ -19,11 +19,14 @@ package http
 import (
 	"flag"
 	"fmt"
+	"github.com/Clymene-project/Clymene/pkg/tlscfg"
 	"github.com/Clymene-project/Clymene/pkg/version"
 	"github.com/spf13/viper"
 	"time"
 )
 
+var tlsFlagsConfig = tlscfg.ClientFlagsConfig{Prefix: hTTPPrefix}
+
 type Options struct {
 	metricsUrl   string
 	logsUrl      string
@@ -31,7 +34,25 @@ type Options struct {
 	timeout      time.Duration
 	maxErrMsgLen int64
 	Encoding     string
-	//TLS          tlscfg.Options
+	TLS          tlscfg.Options
+	Retry        RetryOptions
+	Queue        QueueOptions
+}
+
+// RetryOptions configures the exponential backoff retry behavior of the
+// RemoteWriteClient.
+type RetryOptions struct {
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+// QueueOptions configures the per-shard batching queue in front of the
+// RemoteWriteClient.
+type QueueOptions struct {
+	Capacity          int
+	MaxSamplesPerSend int
+	BatchSendDeadline time.Duration
 }
 
 const (
@@ -42,10 +63,26 @@ const (
 	suffixTimeout      = ".timeout"
 	suffixMaxErrMsgLen = ".max-err-msg-len"
 
+	suffixRetryMaxAttempts = ".retry.max-attempts"
+	suffixRetryMinBackoff  = ".retry.min-backoff"
+	suffixRetryMaxBackoff  = ".retry.max-backoff"
+
+	suffixQueueCapacity          = ".queue.capacity"
+	suffixQueueMaxSamplesPerSend = ".queue.max-samples-per-send"
+	suffixQueueBatchSendDeadline = ".queue.batch-send-deadline"
+
 	defaultClymeneGatewayUrl     = "http://localhost:15611/api/metrics"
 	defaultClymeneGatewayLogsUrl = "http://localhost:15611/api/logs"
 	defaultTimeout               = 10 * time.Second
 	defaultMaxErrMsgLen          = 256
+
+	defaultRetryMaxAttempts = 5
+	defaultRetryMinBackoff  = 100 * time.Millisecond
+	defaultRetryMaxBackoff  = 10 * time.Second
+
+	defaultQueueCapacity          = 10000
+	defaultQueueMaxSamplesPerSend = 2000
+	defaultQueueBatchSendDeadline = 5 * time.Second
 )
 
 // AddFlags adds flags for Options.
@@ -75,7 +112,37 @@ func AddFlags(flagSet *flag.FlagSet) {
 		defaultMaxErrMsgLen,
 		"Maximum length of error message",
 	)
-	//tlsFlagsConfig.AddFlags(flagSet)
+	flagSet.Int(
+		hTTPPrefix+suffixRetryMaxAttempts,
+		defaultRetryMaxAttempts,
+		"Maximum number of attempts, including the first, for a remote write request that receives a 5xx or 429 response",
+	)
+	flagSet.Duration(
+		hTTPPrefix+suffixRetryMinBackoff,
+		defaultRetryMinBackoff,
+		"Initial backoff before retrying a failed remote write request",
+	)
+	flagSet.Duration(
+		hTTPPrefix+suffixRetryMaxBackoff,
+		defaultRetryMaxBackoff,
+		"Maximum backoff between remote write retries",
+	)
+	flagSet.Int(
+		hTTPPrefix+suffixQueueCapacity,
+		defaultQueueCapacity,
+		"Number of samples to buffer per shard before blocking",
+	)
+	flagSet.Int(
+		hTTPPrefix+suffixQueueMaxSamplesPerSend,
+		defaultQueueMaxSamplesPerSend,
+		"Maximum number of samples per remote write batch",
+	)
+	flagSet.Duration(
+		hTTPPrefix+suffixQueueBatchSendDeadline,
+		defaultQueueBatchSendDeadline,
+		"Maximum time a sample waits in the queue before being sent regardless of batch size",
+	)
+	tlsFlagsConfig.AddFlags(flagSet)
 }
 
 // InitFromViper initializes Options with properties retrieved from Viper.
@@ -85,5 +152,15 @@ func (o *Options) InitFromViper(v *viper.Viper) {
 	o.maxErrMsgLen = v.GetInt64(hTTPPrefix + suffixMaxErrMsgLen)
 	o.timeout = v.GetDuration(hTTPPrefix + suffixTimeout)
 	o.userAgent = v.GetString(hTTPPrefix + suffixUserAgent)
-	//o.TLS = tlsFlagsConfig.InitFromViper(v)
+	o.Retry = RetryOptions{
+		MaxAttempts: v.GetInt(hTTPPrefix + suffixRetryMaxAttempts),
+		MinBackoff:  v.GetDuration(hTTPPrefix + suffixRetryMinBackoff),
+		MaxBackoff:  v.GetDuration(hTTPPrefix + suffixRetryMaxBackoff),
+	}
+	o.Queue = QueueOptions{
+		Capacity:          v.GetInt(hTTPPrefix + suffixQueueCapacity),
+		MaxSamplesPerSend: v.GetInt(hTTPPrefix + suffixQueueMaxSamplesPerSend),
+		BatchSendDeadline: v.GetDuration(hTTPPrefix + suffixQueueBatchSendDeadline),
+	}
+	o.TLS = tlsFlagsConfig.InitFromViper(v)
 }
@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"time"
+
+	"github.com/Clymene-project/Clymene/prompb"
+)
+
+// Queue buffers prompb.TimeSeries in front of a RemoteWriteClient and flushes
+// them as a single WriteRequest either when max-samples-per-send is reached
+// or when batch-send-deadline elapses, whichever comes first.
+type Queue struct {
+	client *RemoteWriteClient
+	opts   QueueOptions
+
+	in   chan prompb.TimeSeries
+	done chan struct{}
+}
+
+// NewQueue creates a Queue that sends batches through client.
+func NewQueue(client *RemoteWriteClient, opts QueueOptions) *Queue {
+	if opts.Capacity <= 0 {
+		opts.Capacity = defaultQueueCapacity
+	}
+	if opts.MaxSamplesPerSend <= 0 {
+		opts.MaxSamplesPerSend = defaultQueueMaxSamplesPerSend
+	}
+	if opts.BatchSendDeadline <= 0 {
+		opts.BatchSendDeadline = defaultQueueBatchSendDeadline
+	}
+	return &Queue{
+		client: client,
+		opts:   opts,
+		in:     make(chan prompb.TimeSeries, opts.Capacity),
+		done:   make(chan struct{}),
+	}
+}
+
+// Enqueue adds a series to the queue, blocking if it is at capacity.
+func (q *Queue) Enqueue(ctx context.Context, ts prompb.TimeSeries) error {
+	select {
+	case q.in <- ts:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shutdownFlushTimeout bounds the final flush issued when Run's context is
+// cancelled, since that context is itself already done and can't be used to
+// send the last batch.
+const shutdownFlushTimeout = 10 * time.Second
+
+// Run drains the queue, flushing batches to the client, until ctx is
+// cancelled. It must be started once per shard.
+func (q *Queue) Run(ctx context.Context) {
+	defer close(q.done)
+
+	ticker := time.NewTicker(q.opts.BatchSendDeadline)
+	defer ticker.Stop()
+
+	var batch []prompb.TimeSeries
+	flush := func(sendCtx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := q.client.Store(sendCtx, &prompb.WriteRequest{Timeseries: batch}); err != nil {
+			droppedSamplesTotal.Add(float64(len(batch)))
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// ctx is already cancelled, so the final flush needs its own
+			// bounded context or the send would fail immediately and drop
+			// whatever was still buffered.
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownFlushTimeout)
+			flush(shutdownCtx)
+			cancel()
+			return
+		case ts := <-q.in:
+			batch = append(batch, ts)
+			if len(batch) >= q.opts.MaxSamplesPerSend {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2021 The Clymene Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Clymene-project/Clymene/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCountingServer(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	return srv, &requests
+}
+
+func TestQueueFlushesOnMaxSamplesPerSend(t *testing.T) {
+	srv, requests := newCountingServer(t)
+	defer srv.Close()
+
+	client, err := NewRemoteWriteClient(srv.URL, &Options{}, PrompbCodec{})
+	require.NoError(t, err)
+
+	q := NewQueue(client, QueueOptions{Capacity: 10, MaxSamplesPerSend: 3, BatchSendDeadline: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	go q.Run(ctx)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, q.Enqueue(ctx, seriesAt(int64(i))))
+	}
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(requests) >= 1
+	}, time.Second, 5*time.Millisecond, "expected a flush once MaxSamplesPerSend was reached")
+
+	cancel()
+}
+
+func TestQueueFlushesOnDeadlineWithoutReachingMaxSamples(t *testing.T) {
+	srv, requests := newCountingServer(t)
+	defer srv.Close()
+
+	client, err := NewRemoteWriteClient(srv.URL, &Options{}, PrompbCodec{})
+	require.NoError(t, err)
+
+	q := NewQueue(client, QueueOptions{Capacity: 10, MaxSamplesPerSend: 1000, BatchSendDeadline: 20 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	go q.Run(ctx)
+
+	require.NoError(t, q.Enqueue(ctx, seriesAt(0)))
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(requests) >= 1
+	}, time.Second, 5*time.Millisecond, "expected the batch deadline to flush a partial batch")
+
+	cancel()
+}
+
+func TestQueueFlushesRemainingBatchOnShutdown(t *testing.T) {
+	srv, requests := newCountingServer(t)
+	defer srv.Close()
+
+	client, err := NewRemoteWriteClient(srv.URL, &Options{}, PrompbCodec{})
+	require.NoError(t, err)
+
+	q := NewQueue(client, QueueOptions{Capacity: 10, MaxSamplesPerSend: 1000, BatchSendDeadline: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(done)
+	}()
+
+	require.NoError(t, q.Enqueue(ctx, seriesAt(0)))
+	// Give Run a moment to pick the sample off the channel before shutting
+	// down, so it lands in the buffered batch rather than racing Enqueue.
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Queue.Run did not stop after its context was cancelled")
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(requests))
+}
+
+func seriesAt(ts int64) prompb.TimeSeries {
+	return prompb.TimeSeries{
+		Labels:  []prompb.Label{{Name: "__name__", Value: "test_metric"}},
+		Samples: []prompb.Sample{{Value: 1, Timestamp: ts}},
+	}
+}